@@ -0,0 +1,128 @@
+package filterdir
+
+import (
+	"sync"
+	"time"
+)
+
+// Controller is a programmatic, frontend-agnostic API for driving a
+// FilterDir's recording session. The gocui console application (built with
+// the "gocui" tag) is one implementation built on top of it; callers that
+// cannot take over the terminal -- an HTTP admin endpoint, a test, a web UI
+// -- can use it directly instead.
+type Controller interface {
+	// Clear empties the current IncludeList.
+	Clear()
+
+	// Save writes the current IncludeList out to Options.ListFileName.
+	Save() error
+
+	// Generate runs vfsgen against the current IncludeList, producing the
+	// static, filtered http.FileSystem implementation.
+	Generate() error
+
+	// Snapshot returns a sorted copy of the files recorded so far.
+	Snapshot() []string
+
+	// Subscribe returns a channel on which a new Snapshot is pushed every
+	// time the recorded file list changes. Subscribers that fall behind
+	// miss intermediate snapshots rather than blocking the controller.
+	Subscribe() <-chan []string
+}
+
+// Controller returns the Controller driving f, starting its background
+// bookkeeping goroutine on first use. Use it to build your own frontend --
+// an HTTP admin endpoint, a test harness, a web UI -- instead of, or
+// alongside, the built-in gocui console application started by Open.
+//
+// Calling Controller consumes the same startOnce slot Open uses to launch
+// the gocui frontend, so a caller that obtains one before serving any
+// requests -- the "drive filterdir from my own HTTP admin endpoint"
+// use case -- gets no competing frontend: Open will neither start gocui
+// (which, built without the "gocui" tag, would otherwise log.Fatal) nor
+// spin up a second, independent consumer of f.requests.
+func (f *FilterDir) Controller() Controller {
+	f.startOnce.Do(func() {})
+	return f.ensureController()
+}
+
+// ensureController starts, on first call, the background goroutine that
+// consumes f.requests, without touching startOnce -- unlike Controller, it
+// does not suppress a gocui/Headless frontend that Open hasn't started yet.
+// Internal callers such as RecordWalk, which only need somewhere for
+// f.requests to go and not a whole frontend replacement, use this instead.
+func (f *FilterDir) ensureController() Controller {
+	f.ctrlOnce.Do(func() {
+		f.ctrl = newController(f)
+	})
+	return f.ctrl
+}
+
+// controller is the default Controller implementation, wrapping the
+// sortedList kept by processRequests with save/generate actions and a
+// simple fan-out for Subscribe.
+type controller struct {
+	fd   *FilterDir
+	list *sortedList
+
+	mu   sync.Mutex
+	subs []chan []string
+}
+
+func newController(fd *FilterDir) *controller {
+	c := &controller{
+		fd:   fd,
+		list: processRequests(fd.IncludeList, fd.requests),
+	}
+	go c.watch()
+	return c
+}
+
+// watch polls the underlying sortedList for changes and fans them out to
+// Subscribe channels. processRequests only exposes a Changed/List pair
+// rather than its own notification channel, so polling is the simplest way
+// to bridge it to Controller's push-based Subscribe.
+func (c *controller) watch() {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for range ticker.C {
+		if c.list.Changed() {
+			c.broadcast(c.Snapshot())
+		}
+	}
+}
+
+func (c *controller) Clear() {
+	c.list.Clear()
+}
+
+func (c *controller) Save() error {
+	return c.fd.saveList(c.list.List())
+}
+
+func (c *controller) Generate() error {
+	return c.fd.generateAssets(c.list.List())
+}
+
+func (c *controller) Snapshot() []string {
+	return c.list.List()
+}
+
+func (c *controller) Subscribe() <-chan []string {
+	ch := make(chan []string, 1)
+	c.mu.Lock()
+	c.subs = append(c.subs, ch)
+	c.mu.Unlock()
+	return ch
+}
+
+func (c *controller) broadcast(list []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ch := range c.subs {
+		select {
+		case ch <- list:
+		default:
+		}
+	}
+}
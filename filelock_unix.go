@@ -0,0 +1,18 @@
+// +build !windows
+
+package filterdir
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive, blocking advisory lock on f using flock(2).
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+// unlockFile releases a lock previously taken by lockFile.
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -57,7 +57,7 @@ assets.go
 
 	import "github.com/jtwatson/filterdir"
 
-	var assets = filterdir.New("gui", filterdir.Options{})
+	var assets = filterdir.NewDir("gui", filterdir.Options{})
 
 Notice the "dev" build tag. Now with one small change to our original http.FileServer
 we can use "assets" as follows:
@@ -77,7 +77,7 @@ main.go
 
 Now we can run our program:
 
-	go run -tags=dev main.go assets.go
+	go run -tags=dev,gocui main.go assets.go
 
 As soon as FilterDir receives its first request, it will start a console application
 that displays a summary of the files requested. It also has options as follows:
@@ -87,21 +87,24 @@ that displays a summary of the files requested. It also has options as follows:
 	g : Generate go code that statically implements all files in list (using shurcooL/vfsgen)
 	q : Quit
 
+The console application lives behind the "gocui" build tag so that consumers who
+don't want filterdir taking over their terminal -- servers, tests, custom web UIs --
+aren't forced to pull in gocui. Built without the tag, or with Options.Headless set,
+call FilterDir.Controller() (or Flush, in the headless case) to drive the same
+recording session programmatically instead.
 */
 package filterdir
 
 import (
 	"html/template"
-	"log"
 	"net/http"
 	"os"
+	"path"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
-	"golang.org/x/net/context"
-
-	"github.com/jroimartin/gocui"
 	"github.com/shurcooL/vfsgen"
 )
 
@@ -110,10 +113,15 @@ import (
 type FilterDir struct {
 	loadOnce  sync.Once
 	startOnce sync.Once
-	dir       http.Dir
+	ctrlOnce  sync.Once
+	dir       http.FileSystem
 	options   Options
 	requests  chan string
 	include   map[string]struct{}
+	ctrl      Controller
+	mounts    []mountPoint
+	resolved  sync.Map
+	synthetic map[string][]byte
 
 	// FilterMode enables the filter so only files found in IncludeList
 	// will be returned.
@@ -124,10 +132,19 @@ type FilterDir struct {
 	IncludeList []string
 }
 
-// New returns a newly instanciated FilterDir with dir as the root directory used to server files.
-func New(dir string, opt Options) *FilterDir {
+// New returns a newly instanciated FilterDir serving files out of fs. fs can
+// be an http.Dir, the result of Union, or any other http.FileSystem --
+// including one of your own, or one from a package such as mapfs.
+func New(fs http.FileSystem, opt Options) *FilterDir {
 	opt.fillMissing()
-	return &FilterDir{dir: http.Dir(dir), options: opt, requests: make(chan string, 100)}
+	return &FilterDir{dir: fs, options: opt, requests: make(chan string, 100)}
+}
+
+// NewDir is a convenience wrapper around New for the common case of serving
+// files out of a single directory on disk, equivalent to
+// New(http.Dir(dir), opt).
+func NewDir(dir string, opt Options) *FilterDir {
+	return New(http.Dir(dir), opt)
 }
 
 // Options used by vfsgen when generating the statically implemented virtual filesystem.
@@ -143,26 +160,51 @@ func (f *FilterDir) Options() vfsgen.Options {
 
 // Open attempts to open name, which is a resource under the root dir provided to FilterDir
 func (f *FilterDir) Open(name string) (http.File, error) {
-	file, err := f.dir.Open(name)
-	if err != nil {
-		return nil, err
-	}
 	if f.FilterMode == false {
-		f.startOnce.Do(func() {
-			go f.startGUI()
-		})
-		f.requests <- name
+		file, err := f.openRoot(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if f.options.Headless {
+			f.startOnce.Do(f.startHeadless)
+		} else {
+			f.startOnce.Do(func() {
+				go f.startGUI()
+			})
+		}
+
+		if f.matchesPatterns(name) {
+			f.requests <- name
+			f.discover(name)
+		}
 		return file, nil
 	}
 
 	// We are in FilterMode, so results will be filtered
 	f.loadOnce.Do(f.loadIncludeList)
 
-	if _, ok := f.include[name]; ok {
-		return &File{File: file, name: name, include: f.include}, nil
+	if _, ok := f.include[name]; !ok {
+		return nil, os.ErrNotExist
+	}
+
+	if data, ok := f.synthetic[name]; ok {
+		return newMemFile(name, data, f.options.SourceDateEpoch), nil
 	}
 
-	return nil, os.ErrNotExist
+	file, err := f.openRoot(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		File:         file,
+		name:         name,
+		include:      f.include,
+		reproducible: f.options.Reproducible,
+		modTime:      f.options.SourceDateEpoch,
+		synthetic:    f.synthetic,
+	}, nil
 }
 
 func (f *FilterDir) loadIncludeList() {
@@ -177,38 +219,6 @@ func (f *FilterDir) loadIncludeList() {
 	}
 }
 
-func (f *FilterDir) startGUI() {
-
-	// Process incoming file requests
-	reqs := processRequests(f.IncludeList, f.requests)
-
-	// Create GUI
-	gui := gocui.NewGui()
-	if err := gui.Init(); err != nil {
-		log.Fatal(err)
-	}
-	defer gui.Close()
-
-	// Draw UI
-	gui.SetLayout(layout)
-	gui.Cursor = true
-
-	// Push file list changes to UI
-	ctx, done := context.WithCancel(context.Background())
-	defer done()
-	go pushUpdates(ctx, gui, reqs)
-
-	// Wire up keys to actions
-	if err := bindKeys(gui, reqs, f); err != nil {
-		log.Fatal(err)
-	}
-
-	// Run GUI
-	if err := gui.MainLoop(); err != nil && err != gocui.ErrQuit {
-		log.Fatal(err)
-	}
-}
-
 func (f *FilterDir) saveList(list []string) error {
 	f.IncludeList = list
 
@@ -240,13 +250,31 @@ func (f *FilterDir) saveList(list []string) error {
 }
 
 func (f *FilterDir) generateAssets(list []string) error {
+	if f.options.Reproducible {
+		sort.StringSlice(list).Sort()
+	}
+
+	if f.options.Precompress {
+		precompressed, err := f.precompress(list)
+		if err != nil {
+			return err
+		}
+		list = precompressed
+	}
+
 	f.IncludeList = list
 	f.FilterMode = true
 
-	err := vfsgen.Generate(f, f.Options())
-	if err != nil {
+	if err := vfsgen.Generate(f, f.Options()); err != nil {
 		return err
 	}
+
+	if f.options.Reproducible {
+		if err := f.writeHashes(list); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -256,17 +284,29 @@ func (f *FilterDir) generateAssets(list []string) error {
 // The methods should behave the same as those on an *os.File.
 type File struct {
 	http.File
-	name    string
-	include map[string]struct{}
+	name         string
+	include      map[string]struct{}
+	reproducible bool
+	modTime      time.Time
+	synthetic    map[string][]byte
 }
 
 // Readdir behaves the same way as os.File.Readdir, but additionally
-// filters on IncludeList
+// filters on IncludeList and, since they have no real underlying directory
+// entry of their own, injects any Options.Precompress ".gz"/".br" variants
+// whose path lives directly under this directory. vfsgen discovers files by
+// walking Readdir, so without this step the precompressed variants would
+// never be embedded even though they're in IncludeList and Open'able.
 func (f *File) Readdir(count int) ([]os.FileInfo, error) {
 	// Remove trailing '/' if it is present
 	if f.name[len(f.name)-1:] == "/" {
 		f.name = f.name[:len(f.name)-1]
 	}
+	dirName := f.name
+	if dirName == "" {
+		dirName = "/"
+	}
+
 	info, err := f.File.Readdir(count)
 	var newInfo []os.FileInfo
 	for _, i := range info {
@@ -274,9 +314,29 @@ func (f *File) Readdir(count int) ([]os.FileInfo, error) {
 			newInfo = append(newInfo, i)
 		}
 	}
+
+	for childName, data := range f.synthetic {
+		if path.Dir(childName) != dirName {
+			continue
+		}
+		newInfo = append(newInfo, newMemFile(childName, data, f.modTime))
+	}
+
 	return newInfo, err
 }
 
+// Stat behaves the same way as os.File.Stat, except that in
+// Options.Reproducible mode ModTime is pinned to Options.SourceDateEpoch
+// (the Unix epoch if left zero) so the generated vfsgen output doesn't vary
+// between machines or between runs.
+func (f *File) Stat() (os.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil || !f.reproducible {
+		return info, err
+	}
+	return reproducibleFileInfo{FileInfo: info, modTime: f.modTime}, nil
+}
+
 // Options for code generation.
 type Options struct {
 	// Filename of the generated Go code output (including extension).
@@ -308,6 +368,65 @@ type Options struct {
 	// If left empty, it defaults to "dev".
 	// The build tags syntax is specified by the go tool.
 	ListFileBuildTags string
+
+	// Headless disables the gocui frontend. Instead of starting a console
+	// application, Open records newly-seen paths directly (subject to
+	// IncludePatterns and ExcludePatterns) so filterdir can be driven from
+	// CI pipelines and `go generate` workflows that have no terminal to
+	// attach to. Callers are expected to invoke Flush, typically via the
+	// signal.Notify handler that Open installs automatically, once they are
+	// done exercising the application.
+	Headless bool
+
+	// RecordFile, when set together with Headless, receives one newly-seen
+	// path per line as Open discovers it, appended under an exclusive file
+	// lock. Without Headless it is not written. This is in
+	// addition to, not instead of, the list written by Flush/Save; it is
+	// useful as a running audit log when a process is killed before it has
+	// a chance to shut down cleanly.
+	RecordFile string
+
+	// ExcludePatterns are glob patterns, matched with matchGlob semantics,
+	// for paths that should never be added to IncludeList even though they
+	// were requested. A pattern with no "/" is matched against every
+	// segment of the path, so ".*" excludes anything under a dot-directory
+	// at any depth; a pattern with a "/" matches the full path, where "**"
+	// matches any number of segments. Evaluated after IncludePatterns.
+	ExcludePatterns []string
+
+	// IncludePatterns, when non-empty, restrict recording to paths matching
+	// at least one of the given patterns (matchGlob semantics, see
+	// ExcludePatterns). Leave empty to record every requested path, subject
+	// to ExcludePatterns.
+	IncludePatterns []string
+
+	// Resolver, when set, is run over every newly-recorded file so that
+	// assets it references -- but that the browser hasn't requested yet --
+	// are pre-added to IncludeList too. See WebAssetResolver for the
+	// built-in implementation covering HTML, CSS and JS/ES modules.
+	Resolver Resolver
+
+	// Reproducible, when set, makes Generate's output deterministic: every
+	// File.Stat's ModTime is pinned to SourceDateEpoch, and a sidecar
+	// HashesFileName is written with the SHA-256 of every file so that
+	// downstream code can serve strong ETags without reading the file.
+	Reproducible bool
+
+	// SourceDateEpoch pins the ModTime reported by File.Stat when
+	// Reproducible is set. If left zero, the Unix epoch is used.
+	SourceDateEpoch time.Time
+
+	// HashesFileName is the go source file written alongside ListFileName
+	// when Reproducible is set, mapping each file to the hex-encoded
+	// SHA-256 of its contents. If left empty, it defaults to
+	// "_vfsdata_hashes.go".
+	HashesFileName string
+
+	// Precompress, when set, adds a gzip- and brotli-encoded variant of
+	// every file to IncludeList before Generate runs, so the generated
+	// vfsgen filesystem embeds all three. Pair with NegotiatedFileSystem to
+	// serve whichever variant a client's Accept-Encoding allows.
+	Precompress bool
 }
 
 // fillMissing sets default values for mandatory options that are left empty.
@@ -327,6 +446,12 @@ func (opt *Options) fillMissing() {
 	if opt.ListFileBuildTags == "" {
 		opt.ListFileBuildTags = "dev"
 	}
+	if opt.HashesFileName == "" {
+		opt.HashesFileName = "_vfsdata_hashes.go"
+	}
+	if opt.SourceDateEpoch.IsZero() {
+		opt.SourceDateEpoch = time.Unix(0, 0).UTC()
+	}
 }
 
 func processRequests(savedIncludeList []string, requests chan string) *sortedList {
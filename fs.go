@@ -0,0 +1,75 @@
+package filterdir
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// Union returns an http.FileSystem that resolves a name by trying each of
+// fs in order and returning the first successful Open, modelled on
+// golang.org/x/tools/godoc/vfs's NameSpace. It lets callers layer several
+// source directories -- say a node_modules/rxjs checkout, a dist/ build
+// output, and an assets/ directory -- behind a single http.FileSystem so
+// that New can record one unified IncludeList across all of them.
+func Union(fs ...http.FileSystem) http.FileSystem {
+	return unionFS(fs)
+}
+
+type unionFS []http.FileSystem
+
+func (u unionFS) Open(name string) (http.File, error) {
+	var firstErr error
+	for _, fs := range u {
+		file, err := fs.Open(name)
+		if err == nil {
+			return file, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		firstErr = os.ErrNotExist
+	}
+	return nil, firstErr
+}
+
+// mountPoint binds prefix to fs, as recorded by Mount.
+type mountPoint struct {
+	prefix string
+	fs     http.FileSystem
+}
+
+// Mount binds sub so that names under prefix are resolved against it
+// instead of f's root filesystem, again modelled on NameSpace.Bind. Files
+// served through a mount are recorded and filtered exactly like any other
+// path, with the mounted prefix kept in IncludeList. FilterMode opens files
+// through the same mounts, so a saved list is replayed against a FilterDir
+// that declares the same mounts.
+func (f *FilterDir) Mount(prefix string, sub http.FileSystem) {
+	prefix = path.Clean("/" + prefix)
+	f.mounts = append(f.mounts, mountPoint{prefix: prefix, fs: sub})
+}
+
+// openRoot resolves name against f's mounts before falling back to f.dir.
+// The most recently added mount whose prefix matches wins, consistent with
+// NameSpace.Bind where later binds take precedence.
+func (f *FilterDir) openRoot(name string) (http.File, error) {
+	for i := len(f.mounts) - 1; i >= 0; i-- {
+		m := f.mounts[i]
+		if m.prefix == "/" {
+			return m.fs.Open(name)
+		}
+		if name != m.prefix && !strings.HasPrefix(name, m.prefix+"/") {
+			continue
+		}
+		sub := strings.TrimPrefix(name, m.prefix)
+		if sub == "" {
+			sub = "/"
+		}
+		return m.fs.Open(sub)
+	}
+	return f.dir.Open(name)
+}
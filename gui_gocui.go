@@ -0,0 +1,107 @@
+// +build gocui
+
+package filterdir
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jroimartin/gocui"
+)
+
+// startGUI is the gocui-backed console frontend. It is only compiled in
+// when filterdir is built with -tags gocui, which keeps gocui -- and the
+// terminal I/O it takes over -- out of the default build. It is a thin
+// view over Controller: all state lives behind f.Controller(), so any other
+// frontend observes and drives the exact same recording session.
+func (f *FilterDir) startGUI() {
+	ctrl := f.Controller()
+
+	gui := gocui.NewGui()
+	if err := gui.Init(); err != nil {
+		log.Fatal(err)
+	}
+	defer gui.Close()
+
+	gui.SetLayout(layout)
+	gui.Cursor = true
+
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	go pushUpdates(ctx, gui, ctrl)
+
+	if err := bindKeys(gui, ctrl); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := gui.MainLoop(); err != nil && err != gocui.ErrQuit {
+		log.Fatal(err)
+	}
+}
+
+func layout(gui *gocui.Gui) error {
+	maxX, maxY := gui.Size()
+
+	if v, err := gui.SetView("files", 0, 0, maxX-1, maxY-2); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Recorded Files"
+		v.Wrap = true
+	}
+
+	if v, err := gui.SetView("help", 0, maxY-2, maxX-1, maxY); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Frame = false
+		fmt.Fprint(v, "c : clear   s : save   g : generate   q : quit")
+	}
+
+	return nil
+}
+
+// pushUpdates re-renders the "files" view every time ctrl reports a change,
+// until ctx is cancelled.
+func pushUpdates(ctx context.Context, gui *gocui.Gui, ctrl Controller) {
+	updates := ctrl.Subscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case list := <-updates:
+			gui.Execute(func(gui *gocui.Gui) error {
+				v, err := gui.View("files")
+				if err != nil {
+					return err
+				}
+				v.Clear()
+				for _, l := range list {
+					fmt.Fprintln(v, l)
+				}
+				return nil
+			})
+		}
+	}
+}
+
+func bindKeys(gui *gocui.Gui, ctrl Controller) error {
+	bindings := []struct {
+		key rune
+		fn  func(*gocui.Gui, *gocui.View) error
+	}{
+		{'c', func(*gocui.Gui, *gocui.View) error { ctrl.Clear(); return nil }},
+		{'s', func(*gocui.Gui, *gocui.View) error { return ctrl.Save() }},
+		{'g', func(*gocui.Gui, *gocui.View) error { return ctrl.Generate() }},
+		{'q', func(*gocui.Gui, *gocui.View) error { return gocui.ErrQuit }},
+	}
+
+	for _, b := range bindings {
+		if err := gui.SetKeybinding("", b.key, gocui.ModNone, b.fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+// +build !gocui
+
+package filterdir
+
+import "log"
+
+// startGUI is the fallback used when filterdir is built without the
+// "gocui" tag. Build with -tags gocui to get the interactive console
+// frontend, or call Controller() (or set Options.Headless) before serving
+// any requests so Open never reaches this fallback at all.
+func (f *FilterDir) startGUI() {
+	log.Fatal("filterdir: built without the gocui frontend; rebuild with -tags gocui, or call Controller()/set Options.Headless before the first request")
+}
@@ -0,0 +1,160 @@
+package filterdir
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+)
+
+// startHeadless is the Headless-mode counterpart to startGUI. Rather than
+// maintaining its own copy of the recorded list, it drives the same
+// Controller a programmatic caller would use, which gives it a
+// data-race-free view of IncludeList. It calls ensureController, not
+// Controller, because startHeadless itself runs inside f.startOnce.Do --
+// Controller re-enters that same Do to suppress gocui, and sync.Once.Do is
+// not reentrant, so calling it here would deadlock on the first Open.
+func (f *FilterDir) startHeadless() {
+	ctrl := f.ensureController()
+
+	if f.options.RecordFile != "" {
+		go f.recordToFile(ctrl)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		if err := f.Flush(); err != nil {
+			log.Printf("filterdir: flush on shutdown: %v", err)
+		}
+		os.Exit(0)
+	}()
+}
+
+// recordToFile appends every newly-seen path in ctrl's snapshots to
+// Options.RecordFile, under an exclusive file lock, as it is discovered.
+func (f *FilterDir) recordToFile(ctrl Controller) {
+	seen := make(map[string]struct{})
+	for list := range ctrl.Subscribe() {
+		for _, name := range list {
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			if err := appendRecord(f.options.RecordFile, name); err != nil {
+				log.Printf("filterdir: recording %s: %v", name, err)
+			}
+		}
+	}
+}
+
+// matchesPatterns reports whether name should be recorded into IncludeList,
+// based on Options.IncludePatterns and Options.ExcludePatterns, compared
+// using matchGlob. When IncludePatterns is non-empty, name must match at
+// least one of them; ExcludePatterns are then applied on top and always win.
+func (f *FilterDir) matchesPatterns(name string) bool {
+	if len(f.options.IncludePatterns) > 0 {
+		var matched bool
+		for _, pattern := range f.options.IncludePatterns {
+			if matchGlob(pattern, name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	for _, pattern := range f.options.ExcludePatterns {
+		if matchGlob(pattern, name) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchGlob reports whether the rooted path name (e.g. "/dist/app.js")
+// matches pattern, using gitignore-like semantics rather than a plain
+// path.Match on the full path -- plain path.Match would make "*.js" fail to
+// match "/dist/app.js", since "*" never crosses a "/".
+//
+// A pattern with no "/" is matched against every segment of name, so
+// "*.js" matches "/dist/app.js" and ".*" matches "/dist/.cache/x" via its
+// ".cache" segment, excluding the whole subtree. A pattern with a "/" is
+// matched segment-by-segment against the full path, where a "**" segment
+// matches zero or more segments of name -- so "/dist/**" matches
+// everything under dist, at any depth. Non-"**" segments are compared with
+// path.Match.
+func matchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		for _, seg := range strings.Split(strings.Trim(name, "/"), "/") {
+			if ok, _ := path.Match(pattern, seg); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	patternSegs := strings.Split(strings.Trim(pattern, "/"), "/")
+	nameSegs := strings.Split(strings.Trim(name, "/"), "/")
+	return matchSegments(patternSegs, nameSegs)
+}
+
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+
+	if pattern[0] == "**" {
+		if matchSegments(pattern[1:], name) {
+			return true
+		}
+		if len(name) == 0 {
+			return false
+		}
+		return matchSegments(pattern, name[1:])
+	}
+
+	if len(name) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
+
+// Flush writes the current IncludeList out to Options.ListFileName, the same
+// file produced by pressing "s" in the gocui frontend. It is meant to be
+// called when running with Options.Headless set, typically from the signal
+// handler that Open installs automatically, so that CI pipelines and
+// `go generate` invocations persist the recorded list on shutdown.
+func (f *FilterDir) Flush() error {
+	return f.Controller().Save()
+}
+
+// appendRecord appends line to the named file, creating it if necessary,
+// holding an exclusive file lock for the duration of the write so that
+// concurrent filterdir processes recording into the same RecordFile don't
+// interleave or clobber each other.
+func appendRecord(name, line string) error {
+	rf, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer rf.Close()
+
+	if err := lockFile(rf); err != nil {
+		return err
+	}
+	defer unlockFile(rf)
+
+	_, err = fmt.Fprintln(rf, line)
+	return err
+}
@@ -0,0 +1,149 @@
+package filterdir
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"sort"
+)
+
+// FS returns an io/fs.FS -- also implementing fs.ReadDirFS and fs.StatFS --
+// over the same filtered view Open provides, so f's recorded or filtered
+// files can be used anywhere the io/fs ecosystem is expected: embed-style
+// tooling, http.FS, text/template.ParseFS, and so on.
+func (f *FilterDir) FS() fs.FS {
+	return httpFS{open: httpFileSystemFunc(f.Open)}
+}
+
+// RecordWalk pre-populates IncludeList by walking the root filesystem --
+// the one passed to New, plus anything added with Mount -- and feeding
+// every file whose path matches at least one of patterns (matchGlob syntax:
+// a pattern with no "/" matches any path segment, e.g. "*.js" matches
+// "/dist/app.js"; a pattern with a "/" matches the full path, where "**"
+// recurses through any number of segments, e.g. "/dist/**") into the same
+// request stream Open uses, without a round-trip through Open itself.
+// That's what lets the headline use case -- "include everything under
+// dist/ matching *.js and *.css, plus whatever the browser requests" --
+// land in a single IncludeList: matches go through Controller/Save/Generate
+// exactly like a directly-requested path would.
+//
+// RecordWalk starts the same background consumer Controller does (without
+// suppressing a gocui/Headless frontend Open hasn't started yet), so it is
+// safe to call before, after, or interleaved with serving requests.
+// Matches are recorded asynchronously, though: call Controller().Snapshot,
+// Save or Generate only after RecordWalk returns AND its matches have had
+// a chance to drain, e.g. via Controller().Subscribe, not immediately
+// after the call.
+func (f *FilterDir) RecordWalk(patterns ...string) error {
+	f.ensureController()
+
+	root := httpFS{open: httpFileSystemFunc(f.openRoot)}
+
+	walk := func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		httpPath := toHTTPPath(name)
+
+		for _, pattern := range patterns {
+			if matchGlob(pattern, httpPath) {
+				f.requests <- httpPath
+				break
+			}
+		}
+
+		return nil
+	}
+
+	if err := fs.WalkDir(root, ".", walk); err != nil {
+		return err
+	}
+	// A mount whose prefix the root filesystem's directories don't list is
+	// not reached by the walk above, so each mount is walked from its prefix.
+	for _, m := range f.mounts {
+		if m.prefix == "/" {
+			continue
+		}
+		if err := fs.WalkDir(root, m.prefix[1:], walk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// httpFileSystemFunc adapts a plain Open function to http.FileSystem.
+type httpFileSystemFunc func(name string) (http.File, error)
+
+func (fn httpFileSystemFunc) Open(name string) (http.File, error) {
+	return fn(name)
+}
+
+// httpFS adapts an http.FileSystem to io/fs.FS, fs.ReadDirFS and fs.StatFS.
+// http.FileSystem paths are rooted ("/foo/bar"); io/fs paths are relative
+// and use "." for the root, so toHTTPPath bridges the two conventions.
+type httpFS struct {
+	open http.FileSystem
+}
+
+func (h httpFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return h.open.Open(toHTTPPath(name))
+}
+
+func (h httpFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	dir, err := h.open.Open(toHTTPPath(name))
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+
+	infos, err := dir.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = dirEntry{info}
+	}
+	return entries, nil
+}
+
+func (h httpFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	file, err := h.open.Open(toHTTPPath(name))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return file.Stat()
+}
+
+// dirEntry adapts an os.FileInfo, as returned by http.File.Readdir, to
+// fs.DirEntry.
+type dirEntry struct{ os.FileInfo }
+
+func (d dirEntry) Type() fs.FileMode          { return d.FileInfo.Mode().Type() }
+func (d dirEntry) Info() (fs.FileInfo, error) { return d.FileInfo, nil }
+
+func toHTTPPath(name string) string {
+	if name == "." {
+		return "/"
+	}
+	return "/" + name
+}
@@ -0,0 +1,182 @@
+package filterdir
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// reproducibleFileInfo overrides ModTime on an os.FileInfo so that a File in
+// Options.Reproducible mode reports a constant, rather than the underlying
+// filesystem's, modification time.
+type reproducibleFileInfo struct {
+	os.FileInfo
+	modTime time.Time
+}
+
+func (i reproducibleFileInfo) ModTime() time.Time { return i.modTime }
+
+// writeHashes writes Options.HashesFileName, mapping every file in list to
+// the hex-encoded SHA-256 of its contents.
+func (f *FilterDir) writeHashes(list []string) error {
+	hashes := make(map[string]string, len(list))
+	for _, name := range list {
+		contents, err := f.readFile(name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(contents)
+		hashes[name] = hex.EncodeToString(sum[:])
+	}
+
+	hf, err := os.Create(f.options.HashesFileName)
+	if err != nil {
+		return err
+	}
+	defer hf.Close()
+
+	return hashesTemplate.Execute(hf, struct {
+		Options
+		Hashes map[string]string
+	}{f.options, hashes})
+}
+
+var hashesTemplate = template.Must(template.New("hashes").Parse(`// Code generated by FilterDir
+
+{{with .ListFileBuildTags}}// +build {{.}}
+
+{{end}}package {{.PackageName}}
+
+// {{.VariableName}}Hashes maps every path in {{.VariableName}}.IncludeList to
+// the hex-encoded SHA-256 of its contents.
+var {{.VariableName}}Hashes = map[string]string{
+{{range $name, $sum := .Hashes}}	"{{$name}}": "{{$sum}}",
+{{end}}}
+`))
+
+// precompress returns list extended with a ".gz" and ".br" sibling of every
+// entry, registering their compressed contents as synthetic files so that
+// Open (and therefore vfsgen.Generate) can serve them without a round-trip
+// through the real filesystem.
+func (f *FilterDir) precompress(list []string) ([]string, error) {
+	if f.synthetic == nil {
+		f.synthetic = make(map[string][]byte)
+	}
+
+	extended := make([]string, 0, len(list)*3)
+	for _, name := range list {
+		extended = append(extended, name)
+
+		contents, err := f.readFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		gz, err := gzipBytes(contents)
+		if err != nil {
+			return nil, err
+		}
+		f.synthetic[name+".gz"] = gz
+		extended = append(extended, name+".gz")
+
+		br, err := brotliBytes(contents)
+		if err != nil {
+			return nil, err
+		}
+		f.synthetic[name+".br"] = br
+		extended = append(extended, name+".br")
+	}
+
+	return extended, nil
+}
+
+func gzipBytes(contents []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(contents); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func brotliBytes(contents []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := brotli.NewWriter(&buf)
+	if _, err := w.Write(contents); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// NegotiatedFileSystem wraps fs so that Open transparently serves the
+// ".br" or ".gz" sibling of name -- as produced by Options.Precompress --
+// when acceptEncoding allows it, in that preference order, falling back to
+// name itself when neither exists. Construct one per request, typically
+// from an http.Handler, with r.Header.Get("Accept-Encoding").
+func NegotiatedFileSystem(fs http.FileSystem, acceptEncoding string) http.FileSystem {
+	return negotiatedFS{fs: fs, accept: acceptEncoding}
+}
+
+type negotiatedFS struct {
+	fs     http.FileSystem
+	accept string
+}
+
+func (n negotiatedFS) Open(name string) (http.File, error) {
+	for _, enc := range [...]string{"br", "gzip"} {
+		if !strings.Contains(n.accept, enc) {
+			continue
+		}
+		suffix := ".gz"
+		if enc == "br" {
+			suffix = ".br"
+		}
+		if file, err := n.fs.Open(name + suffix); err == nil {
+			return file, nil
+		}
+	}
+	return n.fs.Open(name)
+}
+
+// memFile is an http.File backed entirely by an in-memory byte slice, used
+// to serve the synthetic precompressed variants Options.Precompress adds to
+// IncludeList.
+type memFile struct {
+	*bytes.Reader
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func newMemFile(name string, data []byte, modTime time.Time) *memFile {
+	return &memFile{Reader: bytes.NewReader(data), name: name, size: int64(len(data)), modTime: modTime}
+}
+
+func (f *memFile) Close() error { return nil }
+
+func (f *memFile) Readdir(count int) ([]os.FileInfo, error) { return nil, io.EOF }
+
+func (f *memFile) Stat() (os.FileInfo, error) { return f, nil }
+
+func (f *memFile) Name() string       { return path.Base(f.name) }
+func (f *memFile) Size() int64        { return f.size }
+func (f *memFile) Mode() os.FileMode  { return 0444 }
+func (f *memFile) ModTime() time.Time { return f.modTime }
+func (f *memFile) IsDir() bool        { return false }
+func (f *memFile) Sys() interface{}   { return nil }
@@ -0,0 +1,242 @@
+package filterdir
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Resolver discovers additional assets referenced by a file as it is
+// recorded, so they can be pre-added to IncludeList even though the
+// browser never requested them directly -- source maps, lazy chunks, and
+// CSS or JS only reachable via @import or a dynamic import(). open lets a
+// Resolver read other files from the same http.FileSystem FilterDir
+// serves from, for example to probe candidate extensions or read a
+// package.json, without the Resolver itself needing to be a FilterDir.
+type Resolver interface {
+	// Resolve returns the paths referenced by the file at name, relative
+	// to the root FilterDir serves from.
+	Resolve(open func(name string) ([]byte, error), name string, contents []byte) []string
+}
+
+// discover runs f.options.Resolver, if set, over name and feeds every
+// newly-discovered path matching Options.IncludePatterns/ExcludePatterns --
+// the same gate Open applies to directly-requested paths -- into
+// f.requests before recursing into it in turn. A file is only ever
+// resolved once per FilterDir, which both bounds the recursion and avoids
+// re-parsing files that have already been visited.
+func (f *FilterDir) discover(name string) {
+	if f.options.Resolver == nil {
+		return
+	}
+	if _, loaded := f.resolved.LoadOrStore(name, struct{}{}); loaded {
+		return
+	}
+
+	contents, err := f.readFile(name)
+	if err != nil {
+		return
+	}
+
+	for _, dep := range f.options.Resolver.Resolve(f.readFile, name, contents) {
+		if !f.matchesPatterns(dep) {
+			continue
+		}
+		f.requests <- dep
+		f.discover(dep)
+	}
+}
+
+// readFile returns the full contents of name, consulting f.synthetic first
+// since synthetic paths -- the ".gz"/".br" variants Options.Precompress
+// adds -- have no entry on the real filesystem for openRoot to find.
+func (f *FilterDir) readFile(name string) ([]byte, error) {
+	if data, ok := f.synthetic[name]; ok {
+		return data, nil
+	}
+
+	file, err := f.openRoot(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ioutil.ReadAll(file)
+}
+
+var (
+	htmlRefRE   = regexp.MustCompile(`(?i)<(?:script|img)\b[^>]*?\ssrc\s*=\s*["']([^"']+)["']|<link\b[^>]*?\shref\s*=\s*["']([^"']+)["']`)
+	cssURLRE    = regexp.MustCompile(`url\(\s*["']?([^"')]+)["']?\s*\)`)
+	cssImportRE = regexp.MustCompile(`@import\s+["']([^"']+)["']`)
+	jsImportRE  = regexp.MustCompile(`import\s+(?:[^"'()]+\s+from\s+)?["']([^"']+)["']|import\(\s*["']([^"']+)["']\s*\)|require\(\s*["']([^"']+)["']\s*\)`)
+)
+
+// WebAssetResolver is the built-in Resolver for HTML, CSS and JS/ES module
+// files. It follows the same references a browser would fetch on their
+// behalf: <script src>, <link href> and <img src> in HTML; url(...) and
+// @import in CSS; and import, dynamic import() and require() specifiers in
+// JS. Bare specifiers (anything not starting with "." or "/") are resolved
+// against NodeModulesRoot, honoring package.json's "exports", "module" and
+// "main" fields in that order and falling back to index.js.
+type WebAssetResolver struct {
+	// NodeModulesRoot is the directory, relative to the root FilterDir
+	// serves from, that bare import specifiers are resolved against. It
+	// defaults to "/node_modules".
+	NodeModulesRoot string
+}
+
+// Resolve implements Resolver.
+func (r WebAssetResolver) Resolve(open func(string) ([]byte, error), name string, contents []byte) []string {
+	var specs []string
+	switch path.Ext(name) {
+	case ".html", ".htm":
+		for _, m := range htmlRefRE.FindAllStringSubmatch(string(contents), -1) {
+			specs = append(specs, firstNonEmpty(m[1], m[2]))
+		}
+	case ".css":
+		for _, m := range cssURLRE.FindAllStringSubmatch(string(contents), -1) {
+			specs = append(specs, m[1])
+		}
+		for _, m := range cssImportRE.FindAllStringSubmatch(string(contents), -1) {
+			specs = append(specs, m[1])
+		}
+	case ".js", ".mjs", ".jsx", ".ts", ".tsx":
+		for _, m := range jsImportRE.FindAllStringSubmatch(string(contents), -1) {
+			if spec := firstNonEmpty(m[1], m[2], m[3]); spec != "" {
+				specs = append(specs, spec)
+			}
+		}
+	default:
+		return nil
+	}
+
+	dir := path.Dir(name)
+	root := r.NodeModulesRoot
+	if root == "" {
+		root = "/node_modules"
+	}
+
+	var refs []string
+	for _, spec := range specs {
+		if isURLSpec(spec) {
+			continue
+		}
+
+		var ref string
+		if strings.HasPrefix(spec, ".") || strings.HasPrefix(spec, "/") {
+			ref = r.resolveExtension(open, path.Clean(path.Join(dir, spec)))
+		} else {
+			ref = r.resolveModule(open, root, spec)
+		}
+		if ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+func isURLSpec(spec string) bool {
+	return strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://") ||
+		strings.HasPrefix(spec, "//") || strings.HasPrefix(spec, "data:")
+}
+
+// resolveExtension returns p if it exists, otherwise the first of
+// p+".js", p+".mjs" or p+"/index.js" that does, or "" if none of them do.
+// A candidate is only ever returned once open has confirmed it's there, so
+// callers never feed a non-existent path into IncludeList.
+func (r WebAssetResolver) resolveExtension(open func(string) ([]byte, error), p string) string {
+	if _, err := open(p); err == nil {
+		return p
+	}
+	for _, suffix := range []string{".js", ".mjs", "/index.js"} {
+		if _, err := open(p + suffix); err == nil {
+			return p + suffix
+		}
+	}
+	return ""
+}
+
+// resolveModule resolves a bare specifier such as "rxjs/operators" against
+// root, honoring the package's package.json the same way Node does, or
+// returns "" if no candidate the package.json (or its absence) suggests
+// actually exists.
+func (r WebAssetResolver) resolveModule(open func(string) ([]byte, error), root, spec string) string {
+	pkgDir := path.Join(root, spec)
+
+	data, err := open(path.Join(pkgDir, "package.json"))
+	if err != nil {
+		return r.resolveExtension(open, path.Join(pkgDir, "index.js"))
+	}
+
+	var pkg struct {
+		Exports json.RawMessage `json:"exports"`
+		Module  string          `json:"module"`
+		Main    string          `json:"main"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return r.resolveExtension(open, path.Join(pkgDir, "index.js"))
+	}
+
+	var candidates []string
+	if entry := packageExportsEntry(pkg.Exports); entry != "" {
+		candidates = append(candidates, path.Join(pkgDir, entry))
+	}
+	if pkg.Module != "" {
+		candidates = append(candidates, path.Join(pkgDir, pkg.Module))
+	}
+	if pkg.Main != "" {
+		candidates = append(candidates, path.Join(pkgDir, pkg.Main))
+	}
+
+	for _, candidate := range candidates {
+		if _, err := open(candidate); err == nil {
+			return candidate
+		}
+	}
+
+	return r.resolveExtension(open, path.Join(pkgDir, "index.js"))
+}
+
+// packageExportsEntry extracts the "." entry point from a package.json
+// "exports" field, which may be a bare string or a conditions object.
+func packageExportsEntry(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var entry string
+	if json.Unmarshal(raw, &entry) == nil {
+		return entry
+	}
+
+	var conditions map[string]json.RawMessage
+	if json.Unmarshal(raw, &conditions) != nil {
+		return ""
+	}
+	dot, ok := conditions["."]
+	if !ok {
+		return ""
+	}
+	if json.Unmarshal(dot, &entry) == nil {
+		return entry
+	}
+	var dotConditions map[string]string
+	if json.Unmarshal(dot, &dotConditions) == nil {
+		for _, key := range []string{"import", "module", "default", "require"} {
+			if v, ok := dotConditions[key]; ok {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}